@@ -0,0 +1,20 @@
+package localdriver
+
+import "os"
+
+//go:generate counterfeiter -o ./localdriverfakes/fake_file_system.go . FileSystem
+
+// FileSystem abstracts the bits of the os and path/filepath packages the
+// driver needs, so tests can exercise error paths (and stall specific
+// calls) without touching a real disk.
+type FileSystem interface {
+	Abs(path string) (string, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Symlink(oldname, newname string) error
+	RemoveAll(path string) error
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	ReadFile(filename string) ([]byte, error)
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+}
@@ -0,0 +1,376 @@
+package localdriver
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/cloudfoundry-incubator/localdriver/metadatastore"
+	"github.com/cloudfoundry-incubator/localdriver/volumestore"
+	"github.com/cloudfoundry-incubator/voldriver"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultScope is the Capabilities scope LocalDriver reports unless
+// overridden with WithScope - each instance mounts volumes onto its own
+// local filesystem, so "local" rather than "global" is the correct
+// default.
+const DefaultScope = "local"
+
+type LocalDriver struct {
+	store         *volumestore.Store
+	metadataStore metadatastore.Store
+	scope         string
+
+	// persistMu serializes persist, so two concurrent mutations (to
+	// different volumes, under volumestore's own per-volume locking)
+	// can't race to snapshot-and-save out of order and have the one
+	// with the older snapshot win the rename and drop the other's
+	// update.
+	persistMu sync.Mutex
+}
+
+// Option configures optional LocalDriver behavior at construction time.
+type Option func(*LocalDriver)
+
+// WithScope overrides the Scope LocalDriver reports from Capabilities.
+// Drivers backed by shared storage (instead of the local filesystem)
+// should pass WithScope("global").
+func WithScope(scope string) Option {
+	return func(d *LocalDriver) {
+		d.scope = scope
+	}
+}
+
+// NewLocalDriver constructs a LocalDriver and rehydrates it from
+// metadataStore: every volume metadataStore.Load returns is registered
+// with the in-memory store, starting out unmounted, since a restarted
+// process's symlinks are gone regardless of what was mounted before.
+func NewLocalDriver(logger lager.Logger, fileSystem FileSystem, metadataStore metadatastore.Store, mountDir string, opts ...Option) *LocalDriver {
+	logger = logger.Session("new-local-driver")
+
+	d := &LocalDriver{
+		store:         volumestore.NewStore(fileSystem, mountDir),
+		metadataStore: metadataStore,
+		scope:         DefaultScope,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	volumes, err := metadataStore.Load()
+	if err != nil {
+		logger.Error("load-failed", err)
+		return d
+	}
+
+	for _, meta := range volumes {
+		if err := d.store.Create(logger, meta.Name, meta.VolumeID, meta.PasscodeHash, meta.Labels); err != nil {
+			logger.Error("rehydrate-volume-failed", err, lager.Data{"volume": meta.Name})
+		}
+	}
+
+	return d
+}
+
+func (d *LocalDriver) Activate(logger lager.Logger) voldriver.ActivateResponse {
+	return voldriver.ActivateResponse{
+		Implements: []string{"VolumeDriver"},
+	}
+}
+
+func (d *LocalDriver) Capabilities(logger lager.Logger) voldriver.CapabilitiesResponse {
+	return voldriver.CapabilitiesResponse{
+		Capabilities: voldriver.CapabilityInfo{Scope: d.scope},
+	}
+}
+
+func (d *LocalDriver) Create(logger lager.Logger, req voldriver.CreateRequest) voldriver.ErrorResponse {
+	logger = logger.Session("create", lager.Data{"volume": req.Name})
+
+	volumeID, ok := req.Opts["volume_id"].(string)
+	if !ok {
+		return voldriver.ErrorResponse{Err: "Missing mandatory 'volume_id' field in 'Opts'"}
+	}
+
+	var passcode string
+	if passcodeOpt, set := req.Opts["passcode"]; set {
+		if passcode, ok = passcodeOpt.(string); !ok {
+			return voldriver.ErrorResponse{Err: "Opts.passcode must be a string value"}
+		}
+	}
+
+	var labels map[string]string
+	if labelsOpt, set := req.Opts["labels"]; set {
+		rawLabels, ok := labelsOpt.(map[string]interface{})
+		if !ok {
+			return voldriver.ErrorResponse{Err: "Opts.labels must be a map of string to string"}
+		}
+		labels = make(map[string]string, len(rawLabels))
+		for k, v := range rawLabels {
+			value, ok := v.(string)
+			if !ok {
+				return voldriver.ErrorResponse{Err: "Opts.labels must be a map of string to string"}
+			}
+			labels[k] = value
+		}
+	}
+
+	passcodeHash, err := hashPasscode(passcode)
+	if err != nil {
+		logger.Error("hash-passcode-failed", err)
+		return voldriver.ErrorResponse{Err: "Unable to secure volume passcode"}
+	}
+
+	if err := d.store.Create(logger, req.Name, volumeID, passcodeHash, labels); err != nil {
+		return voldriver.ErrorResponse{Err: err.Error()}
+	}
+
+	d.persist(logger)
+
+	return voldriver.ErrorResponse{}
+}
+
+func (d *LocalDriver) Mount(logger lager.Logger, req voldriver.MountRequest) voldriver.MountResponse {
+	logger = logger.Session("mount", lager.Data{"volume": req.Name})
+
+	v, found := d.store.Get(req.Name)
+	if !found {
+		return voldriver.MountResponse{Err: fmt.Sprintf("Volume '%s' must be created before being mounted", req.Name)}
+	}
+
+	if v.PasscodeHash != "" {
+		passcodeOpt, set := req.Opts["passcode"]
+		if !set {
+			return voldriver.MountResponse{Err: fmt.Sprintf("Volume %s requires a passcode", req.Name)}
+		}
+		passcode, ok := passcodeOpt.(string)
+		if !ok {
+			return voldriver.MountResponse{Err: "Opts.passcode must be a string value"}
+		}
+		if !passcodeMatches(v.PasscodeHash, passcode) {
+			return voldriver.MountResponse{Err: fmt.Sprintf("Volume %s access denied", req.Name)}
+		}
+	}
+
+	mountPath, err := d.store.Mount(logger, req.Name)
+	if err != nil {
+		return voldriver.MountResponse{Err: err.Error()}
+	}
+
+	d.persist(logger)
+
+	return voldriver.MountResponse{Mountpoint: mountPath}
+}
+
+// hashPasscode bcrypt-hashes passcode for storage, leaving an empty
+// passcode as an empty hash so "no passcode required" round-trips
+// through persistence unchanged.
+func hashPasscode(passcode string) (string, error) {
+	if passcode == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// passcodeMatches reports whether passcode hashes to passcodeHash.
+func passcodeMatches(passcodeHash, passcode string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passcodeHash), []byte(passcode)) == nil
+}
+
+func (d *LocalDriver) Unmount(logger lager.Logger, req voldriver.UnmountRequest) voldriver.ErrorResponse {
+	logger = logger.Session("unmount", lager.Data{"volume": req.Name})
+
+	v, found := d.store.Get(req.Name)
+	if !found {
+		return voldriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", req.Name)}
+	}
+
+	if v.MountCount() == 0 {
+		return voldriver.ErrorResponse{Err: "Volume not previously mounted"}
+	}
+
+	if err := d.store.Unmount(logger, req.Name); err != nil {
+		if os.IsNotExist(err) {
+			mountPath := d.store.MountPath(logger, v.VolumeID)
+			return voldriver.ErrorResponse{Err: fmt.Sprintf("Volume %s does not exist (path: %s), nothing to do!", req.Name, mountPath)}
+		}
+		logger.Error("unmount-failed", err)
+		return voldriver.ErrorResponse{Err: "Error establishing whether volume exists"}
+	}
+
+	d.persist(logger)
+
+	return voldriver.ErrorResponse{}
+}
+
+func (d *LocalDriver) Get(logger lager.Logger, req voldriver.GetRequest) voldriver.GetResponse {
+	v, found := d.store.Get(req.Name)
+	if !found {
+		return voldriver.GetResponse{Err: "Volume not found"}
+	}
+
+	mountPath := ""
+	if v.MountCount() > 0 {
+		mountPath = d.store.MountPath(logger, v.VolumeID)
+	}
+
+	return voldriver.GetResponse{Volume: voldriver.VolumeInfo{Name: req.Name, Mountpoint: mountPath}}
+}
+
+func (d *LocalDriver) Path(logger lager.Logger, req voldriver.PathRequest) voldriver.PathResponse {
+	v, found := d.store.Get(req.Name)
+	if !found || v.MountCount() == 0 {
+		return voldriver.PathResponse{Err: fmt.Sprintf("Volume '%s' not found", req.Name)}
+	}
+
+	return voldriver.PathResponse{Mountpoint: d.store.MountPath(logger, v.VolumeID)}
+}
+
+func (d *LocalDriver) List(logger lager.Logger, req voldriver.ListRequest) voldriver.ListResponse {
+	logger = logger.Session("list")
+
+	volumes := []voldriver.VolumeInfo{}
+	for _, v := range d.store.List(filterFromRequest(req.Filters)) {
+		volumes = append(volumes, voldriver.VolumeInfo{Name: v.Name})
+	}
+
+	return voldriver.ListResponse{Volumes: volumes, Warnings: d.store.Warnings(logger)}
+}
+
+// Prune removes every unmounted volume matching req.Filters, freeing its
+// backing directory. Mounted volumes are never pruned, regardless of
+// whether they match the filter.
+func (d *LocalDriver) Prune(logger lager.Logger, req voldriver.PruneRequest) voldriver.PruneResponse {
+	logger = logger.Session("prune")
+
+	requestFilter := filterFromRequest(req.Filters)
+	unmounted := func(v *volumestore.Volume) bool {
+		return v.MountCount() == 0 && (requestFilter == nil || requestFilter(v))
+	}
+
+	deleted, spaceReclaimed, err := d.store.Prune(logger, unmounted)
+	if err != nil {
+		logger.Error("prune-failed", err)
+	}
+
+	if len(deleted) > 0 {
+		d.persist(logger)
+	}
+
+	return voldriver.PruneResponse{VolumesDeleted: deleted, SpaceReclaimed: spaceReclaimed}
+}
+
+// filterFromRequest translates the docker-style filter map List and
+// Prune requests carry into a volumestore.Filter. Multiple values for
+// the same key are OR'd together; different keys are AND'd, matching
+// docker's filter semantics.
+func filterFromRequest(filters map[string][]string) volumestore.Filter {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return func(v *volumestore.Volume) bool {
+		for key, values := range filters {
+			switch key {
+			case "name":
+				if !anyMatch(values, func(val string) bool { return v.Name == val }) {
+					return false
+				}
+			case "label":
+				if !anyMatch(values, func(val string) bool { return matchesLabel(v.Labels, val) }) {
+					return false
+				}
+			case "driver":
+				if !anyMatch(values, func(val string) bool { return volumestore.FilterByDriver(val)(v) }) {
+					return false
+				}
+			case "dangling":
+				if !anyMatch(values, func(val string) bool { return (v.MountCount() == 0) == (val == "true") }) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+func anyMatch(values []string, predicate func(string) bool) bool {
+	for _, value := range values {
+		if predicate(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLabel reports whether labels satisfies a docker-style label
+// filter value, which is either "key=value" (exact match) or bare "key"
+// (presence only).
+func matchesLabel(labels map[string]string, filter string) bool {
+	key, value, hasValue := strings.Cut(filter, "=")
+	if !hasValue {
+		_, ok := labels[key]
+		return ok
+	}
+	return labels[key] == value
+}
+
+func (d *LocalDriver) Remove(logger lager.Logger, req voldriver.RemoveRequest) voldriver.ErrorResponse {
+	logger = logger.Session("remove", lager.Data{"volume": req.Name})
+
+	if req.Name == "" {
+		return voldriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	if _, found := d.store.Get(req.Name); !found {
+		return voldriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", req.Name)}
+	}
+
+	if err := d.store.Remove(logger, req.Name, true); err != nil {
+		return voldriver.ErrorResponse{Err: err.Error()}
+	}
+
+	d.persist(logger)
+
+	return voldriver.ErrorResponse{}
+}
+
+// persist snapshots every known volume's durable fields and saves them
+// through metadataStore. A save failure is logged rather than returned:
+// losing the ability to survive a future restart shouldn't fail an
+// otherwise-successful operation the caller is waiting on.
+//
+// persist is called, unsynchronized, from several operations that are
+// deliberately allowed to run concurrently across different volumes, so
+// the snapshot-and-save has to be serialized here: without it, two
+// concurrent callers can race to save out of order and the one with the
+// older (less complete) snapshot can win, silently dropping the other's
+// update from the persisted file.
+func (d *LocalDriver) persist(logger lager.Logger) {
+	logger = logger.Session("persist")
+
+	d.persistMu.Lock()
+	defer d.persistMu.Unlock()
+
+	volumes := map[string]metadatastore.VolumeMetadata{}
+	for _, v := range d.store.List(nil) {
+		volumes[v.Name] = metadatastore.VolumeMetadata{
+			Name:         v.Name,
+			VolumeID:     v.VolumeID,
+			PasscodeHash: v.PasscodeHash,
+			Labels:       v.Labels,
+		}
+	}
+
+	if err := d.metadataStore.Save(volumes); err != nil {
+		logger.Error("save-failed", err)
+	}
+}
@@ -0,0 +1,98 @@
+package localdriver_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/cloudfoundry-incubator/localdriver"
+	"github.com/cloudfoundry-incubator/localdriver/localdriverfakes"
+	"github.com/cloudfoundry-incubator/voldriver"
+)
+
+// TestUnmountOfOneVolumeDoesNotBlockOthers guards against a regression to
+// a single coarse driver-wide lock: a slow Unmount on one volume must
+// never hold up Get/List/Create/Unmount against other volumes.
+func TestUnmountOfOneVolumeDoesNotBlockOthers(t *testing.T) {
+	logger := lagertest.NewTestLogger("localdriver-concurrency")
+	fakeFileSystem := &localdriverfakes.FakeFileSystem{}
+	driver := localdriver.NewLocalDriver(logger, fakeFileSystem, newFakeMetadataStore(), "/path/to/mount")
+
+	const stalledVolume = "stalled-volume"
+	const otherVolumes = 20
+
+	create := func(name string) {
+		resp := driver.Create(logger, voldriver.CreateRequest{
+			Name: name,
+			Opts: map[string]interface{}{"volume_id": name + "-id"},
+		})
+		if resp.Err != "" {
+			t.Fatalf("create %s: %s", name, resp.Err)
+		}
+	}
+
+	create(stalledVolume)
+	fakeFileSystem.AbsReturns("/path/to/mount", nil)
+	if resp := driver.Mount(logger, voldriver.MountRequest{Name: stalledVolume}); resp.Err != "" {
+		t.Fatalf("mount %s: %s", stalledVolume, resp.Err)
+	}
+
+	for i := 0; i < otherVolumes; i++ {
+		create(otherVolumeName(i))
+	}
+
+	unblockStat := make(chan struct{})
+	statCalled := make(chan struct{}, 1)
+
+	fakeFileSystem.StatStub = func(path string) (os.FileInfo, error) {
+		select {
+		case statCalled <- struct{}{}:
+		default:
+		}
+		<-unblockStat
+		return nil, nil
+	}
+
+	stalledUnmountDone := make(chan struct{})
+	go func() {
+		driver.Unmount(logger, voldriver.UnmountRequest{Name: stalledVolume})
+		close(stalledUnmountDone)
+	}()
+
+	select {
+	case <-statCalled:
+	case <-time.After(time.Second):
+		t.Fatal("stalled Unmount never reached Stat")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < otherVolumes; i++ {
+			name := otherVolumeName(i)
+
+			if resp := driver.Get(logger, voldriver.GetRequest{Name: name}); resp.Err != "" {
+				t.Errorf("get %s: %s", name, resp.Err)
+			}
+			driver.List(logger, voldriver.ListRequest{})
+			create(name + "-again")
+			if resp := driver.Unmount(logger, voldriver.UnmountRequest{Name: name}); resp.Err == "" {
+				t.Errorf("expected %s to not be mounted", name)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("operations on other volumes blocked behind the stalled Unmount")
+	}
+
+	close(unblockStat)
+	<-stalledUnmountDone
+}
+
+func otherVolumeName(i int) string {
+	return "volume-" + string(rune('a'+i))
+}
@@ -0,0 +1,13 @@
+package localdriver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLocalDriver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Local Driver Suite")
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"github.com/cloudfoundry-incubator/localdriver"
 	"github.com/cloudfoundry-incubator/localdriver/localdriverfakes"
+	"github.com/cloudfoundry-incubator/localdriver/metadatastore"
 	"github.com/cloudfoundry-incubator/voldriver"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -18,6 +20,7 @@ import (
 var _ = Describe("Local Driver", func() {
 	var logger lager.Logger
 	var fakeFileSystem *localdriverfakes.FakeFileSystem
+	var fakeMetadataStore *inMemoryMetadataStore
 	var localDriver *localdriver.LocalDriver
 	var mountDir string
 
@@ -27,7 +30,8 @@ var _ = Describe("Local Driver", func() {
 		mountDir = "/path/to/mount"
 
 		fakeFileSystem = &localdriverfakes.FakeFileSystem{}
-		localDriver = localdriver.NewLocalDriver(fakeFileSystem, mountDir)
+		fakeMetadataStore = newFakeMetadataStore()
+		localDriver = localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir)
 	})
 
 	Describe("#Activate", func() {
@@ -36,6 +40,31 @@ var _ = Describe("Local Driver", func() {
 			Expect(len(activateResponse.Implements)).To(BeNumerically(">", 0))
 			Expect(activateResponse.Implements[0]).To(Equal("VolumeDriver"))
 		})
+
+		It("is paired with a Capabilities endpoint reporting local scope", func() {
+			capabilitiesResponse := localDriver.Capabilities(logger)
+			Expect(capabilitiesResponse.Capabilities.Scope).To(Equal("local"))
+		})
+	})
+
+	Describe("#Capabilities", func() {
+		Context("when no scope option is given", func() {
+			It("defaults to local scope", func() {
+				capabilitiesResponse := localDriver.Capabilities(logger)
+				Expect(capabilitiesResponse.Capabilities.Scope).To(Equal("local"))
+			})
+		})
+
+		Context("when constructed with WithScope(\"global\")", func() {
+			BeforeEach(func() {
+				localDriver = localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir, localdriver.WithScope("global"))
+			})
+
+			It("reports the configured scope", func() {
+				capabilitiesResponse := localDriver.Capabilities(logger)
+				Expect(capabilitiesResponse.Capabilities.Scope).To(Equal("global"))
+			})
+		})
 	})
 
 	Describe("Mount", func() {
@@ -276,6 +305,34 @@ var _ = Describe("Local Driver", func() {
 			})
 		})
 
+		Context("when labels are not a map of string to string", func() {
+			It("returns an error", func() {
+				createResponse := localDriver.Create(logger, voldriver.CreateRequest{
+					Name: "volume",
+					Opts: map[string]interface{}{
+						"volume_id": "test-volume-id",
+						"labels":    map[string]interface{}{"env": 42},
+					},
+				})
+
+				Expect(createResponse.Err).To(Equal("Opts.labels must be a map of string to string"))
+			})
+		})
+
+		Context("when labels are provided", func() {
+			It("creates the volume successfully", func() {
+				createResponse := localDriver.Create(logger, voldriver.CreateRequest{
+					Name: "volume",
+					Opts: map[string]interface{}{
+						"volume_id": "test-volume-id",
+						"labels":    map[string]interface{}{"env": "prod"},
+					},
+				})
+
+				Expect(createResponse.Err).To(Equal(""))
+			})
+		})
+
 		Context("when a second create is called with the same volume ID", func() {
 			BeforeEach(func() {
 				createSuccessful(logger, localDriver, fakeFileSystem, "volume", "")
@@ -377,7 +434,7 @@ var _ = Describe("Local Driver", func() {
 			})
 
 			It("returns the list of volumes", func() {
-				listResponse := localDriver.List(logger)
+				listResponse := localDriver.List(logger, voldriver.ListRequest{})
 
 				Expect(listResponse.Err).To(Equal(""))
 				Expect(listResponse.Volumes[0].Name).To(Equal(volumeName))
@@ -391,6 +448,161 @@ var _ = Describe("Local Driver", func() {
 				getUnsuccessful(logger, localDriver, volumeName)
 			})
 		})
+
+		Context("filters", func() {
+			BeforeEach(func() {
+				createResponse := localDriver.Create(logger, voldriver.CreateRequest{
+					Name: "matching-volume",
+					Opts: map[string]interface{}{
+						"volume_id": "matching-volume-id",
+						"labels":    map[string]interface{}{"tier": "gold"},
+					},
+				})
+				Expect(createResponse.Err).To(Equal(""))
+
+				createResponse = localDriver.Create(logger, voldriver.CreateRequest{
+					Name: "other-volume",
+					Opts: map[string]interface{}{"volume_id": "other-volume-id"},
+				})
+				Expect(createResponse.Err).To(Equal(""))
+			})
+
+			It("applies a name filter", func() {
+				listResponse := localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"name": {"matching-volume"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(1))
+				Expect(listResponse.Volumes[0].Name).To(Equal("matching-volume"))
+			})
+
+			It("applies a label filter that matches a volume", func() {
+				listResponse := localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"label": {"tier=gold"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(1))
+				Expect(listResponse.Volumes[0].Name).To(Equal("matching-volume"))
+			})
+
+			It("applies a label filter that matches nothing", func() {
+				listResponse := localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"label": {"tier=silver"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(0))
+			})
+
+			It("applies a driver filter", func() {
+				listResponse := localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"driver": {"local"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(2))
+
+				listResponse = localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"driver": {"other"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(0))
+			})
+
+			It("applies a dangling filter", func() {
+				fakeFileSystem.AbsReturns(mountDir, nil)
+				mountResponse := localDriver.Mount(logger, voldriver.MountRequest{Name: "matching-volume"})
+				Expect(mountResponse.Err).To(Equal(""))
+
+				listResponse := localDriver.List(logger, voldriver.ListRequest{
+					Filters: map[string][]string{"dangling": {"true"}},
+				})
+
+				Expect(listResponse.Volumes).To(HaveLen(1))
+				Expect(listResponse.Volumes[0].Name).To(Equal("other-volume"))
+			})
+		})
+
+		Context("when a volume directory on disk has no in-memory registration", func() {
+			BeforeEach(func() {
+				fakeFileSystem.ReadDirReturns([]os.FileInfo{fakeFileInfo{name: "orphaned-volume-id"}}, nil)
+			})
+
+			It("reports a warning instead of failing", func() {
+				listResponse := localDriver.List(logger, voldriver.ListRequest{})
+
+				Expect(listResponse.Err).To(Equal(""))
+				Expect(listResponse.Warnings).To(ContainElement(ContainSubstring("orphaned-volume-id")))
+			})
+		})
+	})
+
+	Describe("Prune", func() {
+		BeforeEach(func() {
+			for _, name := range []string{"mounted-volume", "unmounted-volume"} {
+				createResponse := localDriver.Create(logger, voldriver.CreateRequest{
+					Name: name,
+					Opts: map[string]interface{}{"volume_id": name + "-id"},
+				})
+				Expect(createResponse.Err).To(Equal(""))
+			}
+
+			fakeFileSystem.AbsReturns(mountDir, nil)
+			mountResponse := localDriver.Mount(logger, voldriver.MountRequest{Name: "mounted-volume"})
+			Expect(mountResponse.Err).To(Equal(""))
+		})
+
+		It("only removes volumes with no current mounts", func() {
+			pruneResponse := localDriver.Prune(logger, voldriver.PruneRequest{})
+
+			Expect(pruneResponse.VolumesDeleted).To(Equal([]string{"unmounted-volume"}))
+
+			getUnsuccessful(logger, localDriver, "unmounted-volume")
+			getResponse := localDriver.Get(logger, voldriver.GetRequest{Name: "mounted-volume"})
+			Expect(getResponse.Err).To(Equal(""))
+		})
+
+		It("does not resurrect a pruned volume across a simulated restart", func() {
+			pruneResponse := localDriver.Prune(logger, voldriver.PruneRequest{})
+			Expect(pruneResponse.VolumesDeleted).To(Equal([]string{"unmounted-volume"}))
+
+			restarted := localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir)
+
+			getUnsuccessful(logger, restarted, "unmounted-volume")
+			getResponse := restarted.Get(logger, voldriver.GetRequest{Name: "mounted-volume"})
+			Expect(getResponse.Err).To(Equal(""))
+		})
+	})
+
+	Describe("Persistence", func() {
+		It("rehydrates volumes (unmounted) across a simulated restart", func() {
+			createSuccessful(logger, localDriver, fakeFileSystem, "test-volume", "my-passcode")
+			mountSuccessful(logger, localDriver, "test-volume", fakeFileSystem, "my-passcode")
+
+			restarted := localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir)
+
+			getResponse := restarted.Get(logger, voldriver.GetRequest{Name: "test-volume"})
+			Expect(getResponse.Err).To(Equal(""))
+			Expect(getResponse.Volume.Mountpoint).To(Equal(""), "a rehydrated volume should start out unmounted")
+		})
+
+		It("accepts the correct passcode across a simulated restart", func() {
+			createSuccessful(logger, localDriver, fakeFileSystem, "test-volume", "my-passcode")
+
+			restarted := localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir)
+
+			mountSuccessful(logger, restarted, "test-volume", fakeFileSystem, "my-passcode")
+		})
+
+		It("does not accept a tampered passcode across a simulated restart", func() {
+			createSuccessful(logger, localDriver, fakeFileSystem, "test-volume", "my-passcode")
+
+			restarted := localdriver.NewLocalDriver(logger, fakeFileSystem, fakeMetadataStore, mountDir)
+
+			mountResponse := restarted.Mount(logger, voldriver.MountRequest{
+				Name: "test-volume",
+				Opts: map[string]interface{}{"passcode": "not-my-passcode"},
+			})
+			Expect(mountResponse.Err).To(Equal("Volume test-volume access denied"))
+		})
 	})
 
 	Describe("Remove", func() {
@@ -451,6 +663,40 @@ var _ = Describe("Local Driver", func() {
 	})
 })
 
+// inMemoryMetadataStore is a minimal metadatastore.Store for tests that
+// need to simulate a driver restart: construct a second LocalDriver
+// against the same store instance and assert it rehydrates correctly.
+type inMemoryMetadataStore struct {
+	volumes map[string]metadatastore.VolumeMetadata
+}
+
+func newFakeMetadataStore() *inMemoryMetadataStore {
+	return &inMemoryMetadataStore{volumes: map[string]metadatastore.VolumeMetadata{}}
+}
+
+func (s *inMemoryMetadataStore) Save(volumes map[string]metadatastore.VolumeMetadata) error {
+	s.volumes = volumes
+	return nil
+}
+
+func (s *inMemoryMetadataStore) Load() (map[string]metadatastore.VolumeMetadata, error) {
+	return s.volumes, nil
+}
+
+// fakeFileInfo is a minimal os.FileInfo for tests that need to inject a
+// directory entry's name without touching a real filesystem.
+type fakeFileInfo struct {
+	name string
+	size int64
+}
+
+func (f fakeFileInfo) Name() string           { return f.name }
+func (f fakeFileInfo) Size() int64            { return f.size }
+func (f fakeFileInfo) Mode() os.FileMode      { return 0 }
+func (f fakeFileInfo) ModTime() (t time.Time) { return t }
+func (f fakeFileInfo) IsDir() bool            { return false }
+func (f fakeFileInfo) Sys() interface{}       { return nil }
+
 func getUnsuccessful(logger lager.Logger, localDriver voldriver.Driver, volumeName string) {
 	getResponse := localDriver.Get(logger, voldriver.GetRequest{
 		Name: volumeName,
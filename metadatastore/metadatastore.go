@@ -0,0 +1,102 @@
+// Package metadatastore persists the set of volumes LocalDriver knows
+// about to disk, so a driver restart doesn't forget a name's mapping to
+// its volume ID and leak an unreferenced _volumes/<id> directory.
+package metadatastore
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+const (
+	StateDir  = "_state"
+	StateFile = "volumes.json"
+)
+
+// VolumeMetadata is the durable record of a single volume. It excludes
+// mount state: a restarted driver's process-local symlinks are gone
+// regardless of what was mounted before, so every rehydrated volume
+// starts out unmounted.
+type VolumeMetadata struct {
+	Name         string
+	VolumeID     string
+	PasscodeHash string
+	Labels       map[string]string `json:",omitempty"`
+}
+
+// FileSystem abstracts the filesystem calls the store needs to read and
+// atomically write its state file.
+type FileSystem interface {
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+	ReadFile(filename string) ([]byte, error)
+	Rename(oldpath, newpath string) error
+}
+
+// Store saves and loads the full set of known volumes, keyed by name.
+type Store interface {
+	Save(volumes map[string]VolumeMetadata) error
+	Load() (map[string]VolumeMetadata, error)
+}
+
+// fileStore is the default Store, backing onto a single JSON file under
+// mountDir/_state.
+type fileStore struct {
+	fileSystem FileSystem
+	mountDir   string
+}
+
+// NewFileStore returns a Store that persists to mountDir/_state/volumes.json.
+func NewFileStore(fileSystem FileSystem, mountDir string) Store {
+	return &fileStore{fileSystem: fileSystem, mountDir: mountDir}
+}
+
+// Load returns every volume previously saved, or an empty map if no state
+// file exists yet.
+func (s *fileStore) Load() (map[string]VolumeMetadata, error) {
+	data, err := s.fileSystem.ReadFile(s.statePath())
+	if os.IsNotExist(err) {
+		return map[string]VolumeMetadata{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := map[string]VolumeMetadata{}
+	if err := json.Unmarshal(data, &volumes); err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+// Save overwrites the state file with volumes, writing to a uniquely
+// named temp file and renaming it into place so a crash mid-write never
+// leaves a truncated or partially-written state file behind. The tmp
+// file name is unique per call so that concurrent Saves never write
+// through the same path and race each other's rename; callers that need
+// the final file to reflect every caller's data still need to serialize
+// their own snapshot-and-save (see LocalDriver.persist).
+func (s *fileStore) Save(volumes map[string]VolumeMetadata) error {
+	data, err := json.Marshal(volumes)
+	if err != nil {
+		return err
+	}
+
+	if err := s.fileSystem.MkdirAll(path.Join(s.mountDir, StateDir), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmpPath := s.statePath() + ".tmp." + strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.Itoa(os.Getpid())
+	if err := s.fileSystem.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+
+	return s.fileSystem.Rename(tmpPath, s.statePath())
+}
+
+func (s *fileStore) statePath() string {
+	return path.Join(s.mountDir, StateDir, StateFile)
+}
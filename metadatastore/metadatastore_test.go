@@ -0,0 +1,76 @@
+package metadatastore_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/localdriver/localdriverfakes"
+	"github.com/cloudfoundry-incubator/localdriver/metadatastore"
+)
+
+func TestLoadWithNoStateFileReturnsEmptyMap(t *testing.T) {
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.ReadFileReturns(nil, os.ErrNotExist)
+	store := metadatastore.NewFileStore(fs, "/path/to/mount")
+
+	volumes, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if len(volumes) != 0 {
+		t.Fatalf("expected no volumes, got %v", volumes)
+	}
+}
+
+func TestSaveThenLoadRoundTrips(t *testing.T) {
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.WriteFileStub = func(filename string, data []byte, perm os.FileMode) error {
+		fs.ReadFileReturns(data, nil)
+		return nil
+	}
+	store := metadatastore.NewFileStore(fs, "/path/to/mount")
+
+	volumes := map[string]metadatastore.VolumeMetadata{
+		"test-volume": {
+			Name:         "test-volume",
+			VolumeID:     "test-volume-id",
+			PasscodeHash: "some-hash",
+			Labels:       map[string]string{"env": "prod"},
+		},
+	}
+
+	if err := store.Save(volumes); err != nil {
+		t.Fatalf("save: %s", err)
+	}
+
+	if got := fs.RenameCallCount(); got != 1 {
+		t.Fatalf("expected Save to write via rename, got %d calls", got)
+	}
+	tmpPath, finalPath := fs.RenameArgsForCall(0)
+	if tmpPath == finalPath {
+		t.Fatalf("expected Save to rename a distinct tmp file into place, got %q -> %q", tmpPath, finalPath)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if got, want := loaded["test-volume"], volumes["test-volume"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected round-tripped volume %+v to equal the saved one %+v", got, want)
+	}
+}
+
+func TestSaveFailsIfWriteFileFails(t *testing.T) {
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.WriteFileReturns(errors.New("disk full"))
+	store := metadatastore.NewFileStore(fs, "/path/to/mount")
+
+	if err := store.Save(map[string]metadatastore.VolumeMetadata{}); err == nil {
+		t.Fatal("expected Save to fail")
+	}
+	if got := fs.RenameCallCount(); got != 0 {
+		t.Fatalf("expected Save not to rename after a failed write, got %d calls", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package syncmap provides a generic, concurrency-safe map keyed by
+// string, used by localdriver to hold per-volume state without forcing
+// every caller through a single coarse lock.
+package syncmap
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// SyncMap is a string-keyed map guarded by a sync.RWMutex. The lock only
+// ever protects the map structure itself (insert/lookup/delete) - callers
+// that need to serialize access to a stored value's contents should hold
+// a lock on the value itself, not on the SyncMap.
+type SyncMap[T any] struct {
+	mu sync.RWMutex
+	m  map[string]T
+}
+
+// New returns an empty SyncMap.
+func New[T any]() *SyncMap[T] {
+	return &SyncMap[T]{m: map[string]T{}}
+}
+
+// Put inserts or overwrites the value stored under key.
+func (s *SyncMap[T]) Put(key string, value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+}
+
+// Get returns the value stored under key, and whether it was present.
+func (s *SyncMap[T]) Get(key string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// GetOrPut returns the value already stored under key, if any; otherwise
+// it stores value and returns it. The lookup and insert happen under a
+// single lock, so concurrent callers racing to create the same key can
+// never both observe "not found" and clobber one another's insert.
+func (s *SyncMap[T]) GetOrPut(key string, value T) (actual T, loaded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.m[key]; ok {
+		return existing, true
+	}
+
+	s.m[key] = value
+	return value, false
+}
+
+// Delete removes key from the map. It is a no-op if key is not present.
+func (s *SyncMap[T]) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, key)
+}
+
+// Keys returns a snapshot of the keys currently in the map.
+func (s *SyncMap[T]) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Len returns the number of entries currently in the map.
+func (s *SyncMap[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.m)
+}
+
+// MarshalJSON snapshots the map under the read lock and marshals it like
+// a plain map[string]T.
+func (s *SyncMap[T]) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(s.m)
+}
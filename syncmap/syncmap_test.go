@@ -0,0 +1,83 @@
+package syncmap_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/localdriver/syncmap"
+)
+
+func TestPutGetDelete(t *testing.T) {
+	m := syncmap.New[int]()
+
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("expected missing key to report not found")
+	}
+
+	m.Put("a", 1)
+	v, ok := m.Get("a")
+	if !ok || v != 1 {
+		t.Fatalf("expected a=1, got %d (ok=%v)", v, ok)
+	}
+
+	m.Delete("a")
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("expected a to be deleted")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	m := syncmap.New[string]()
+	m.Put("a", "one")
+
+	bytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling: %s", err)
+	}
+
+	var decoded map[string]string
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		t.Fatalf("unexpected error unmarshaling: %s", err)
+	}
+	if decoded["a"] != "one" {
+		t.Fatalf("expected a=one, got %q", decoded["a"])
+	}
+}
+
+func TestGetOrPut(t *testing.T) {
+	m := syncmap.New[int]()
+
+	actual, loaded := m.GetOrPut("a", 1)
+	if loaded || actual != 1 {
+		t.Fatalf("expected first GetOrPut to insert 1, got %d (loaded=%v)", actual, loaded)
+	}
+
+	actual, loaded = m.GetOrPut("a", 2)
+	if !loaded || actual != 1 {
+		t.Fatalf("expected second GetOrPut to return the existing 1, got %d (loaded=%v)", actual, loaded)
+	}
+
+	v, ok := m.Get("a")
+	if !ok || v != 1 {
+		t.Fatalf("expected a=1 to remain unchanged, got %d (ok=%v)", v, ok)
+	}
+}
+
+func TestConcurrentAccessDoesNotRace(t *testing.T) {
+	m := syncmap.New[int]()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := string(rune('a' + i%26))
+			m.Put(key, i)
+			m.Get(key)
+			m.Keys()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,395 @@
+// Package volumestore tracks local volumes and their mount reference
+// counts, modeled on docker's volume/store package. It owns the
+// filesystem side effects of mounting (symlink/mkdir) and unmounting
+// (RemoveAll), performing them only on the refCount's 0->1 and 1->0
+// transitions so that repeated Mount/Unmount calls are idempotent even
+// when the underlying filesystem operation fails partway through.
+package volumestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/cloudfoundry-incubator/localdriver/syncmap"
+)
+
+const (
+	VolumesRootDir = "_volumes"
+	MountsRootDir  = "_mounts"
+
+	// LocalDriverName is the value Volume.DriverName takes for every
+	// volume created through this store; it exists so FilterByDriver
+	// behaves the same way it would against a store backing more than
+	// one driver.
+	LocalDriverName = "local"
+)
+
+// FileSystem abstracts the filesystem calls the store needs to perform
+// its mount/unmount side effects.
+type FileSystem interface {
+	Abs(path string) (string, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Symlink(oldname, newname string) error
+	RemoveAll(path string) error
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+}
+
+// Volume is the store's view of a single volume's identity and mount
+// state. Callers outside this package should treat it as read-only.
+type Volume struct {
+	Name         string
+	VolumeID     string
+	PasscodeHash string
+	DriverName   string
+	Labels       map[string]string
+
+	mu       sync.Mutex
+	refCount int
+}
+
+// Filter reports whether a volume should be included in a List result.
+type Filter func(*Volume) bool
+
+// Store holds every known volume, keyed by name, behind a SyncMap so
+// that looking one up never contends with filesystem work being done on
+// another.
+type Store struct {
+	volumes    *syncmap.SyncMap[*Volume]
+	fileSystem FileSystem
+	mountDir   string
+}
+
+// NewStore constructs an empty Store rooted at mountDir.
+func NewStore(fileSystem FileSystem, mountDir string) *Store {
+	return &Store{
+		volumes:    syncmap.New[*Volume](),
+		fileSystem: fileSystem,
+		mountDir:   mountDir,
+	}
+}
+
+// Create registers a new volume. If a volume already exists under name
+// with the same volumeID, Create does nothing; if it exists with a
+// different volumeID, it returns an error.
+//
+// The registration itself goes through GetOrPut so that two concurrent
+// Creates racing for the same new name can't both observe "not found"
+// and have the second clobber the first's *Volume (and any mount state
+// established on it in between) - only one caller ever wins the insert,
+// and every other caller for that name sees and validates against it.
+func (s *Store) Create(logger lager.Logger, name, volumeID, passcodeHash string, labels map[string]string) error {
+	logger = logger.Session("create", lager.Data{"volume": name})
+
+	candidate := &Volume{
+		Name:         name,
+		VolumeID:     volumeID,
+		PasscodeHash: passcodeHash,
+		DriverName:   LocalDriverName,
+		Labels:       labels,
+	}
+
+	existing, loaded := s.volumes.GetOrPut(name, candidate)
+	if loaded {
+		existing.mu.Lock()
+		defer existing.mu.Unlock()
+
+		if existing.VolumeID != volumeID {
+			return fmt.Errorf("Volume '%s' already exists with a different volume ID", name)
+		}
+		return nil
+	}
+
+	if err := s.fileSystem.MkdirAll(s.mountDir, os.ModePerm); err != nil {
+		logger.Error("mkdir-mount-dir-failed", err)
+		s.volumes.Delete(name)
+		return err
+	}
+
+	volumePath := path.Join(s.mountDir, VolumesRootDir, volumeID)
+	if err := s.fileSystem.MkdirAll(volumePath, os.ModePerm); err != nil {
+		logger.Error("mkdir-volume-dir-failed", err)
+		s.volumes.Delete(name)
+		return err
+	}
+
+	return nil
+}
+
+// Mount increments name's refcount, performing the symlink/mkdir only on
+// the 0->1 transition, and returns the resulting mountpoint. If the
+// filesystem call fails on that transition, the refcount is left at 0 so
+// a later retry starts from a clean slate.
+func (s *Store) Mount(logger lager.Logger, name string) (string, error) {
+	logger = logger.Session("mount", lager.Data{"volume": name})
+
+	v, found := s.volumes.Get(name)
+	if !found {
+		return "", fmt.Errorf("Volume '%s' not found", name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	volumePath := s.volumePath(logger, v.VolumeID)
+	mountPath := s.mountPath(logger, v.VolumeID)
+
+	if v.refCount == 0 {
+		if err := s.fileSystem.MkdirAll(path.Dir(mountPath), os.ModePerm); err != nil {
+			logger.Error("mkdir-mounts-dir-failed", err)
+			return "", err
+		}
+		if err := s.fileSystem.MkdirAll(volumePath, os.ModePerm); err != nil {
+			logger.Error("mkdir-volume-dir-failed", err)
+			return "", err
+		}
+		if err := s.fileSystem.Symlink(volumePath, mountPath); err != nil {
+			logger.Error("symlink-failed", err)
+			return "", err
+		}
+	}
+
+	v.refCount++
+	return s.mountPath(logger, v.VolumeID), nil
+}
+
+// Unmount decrements name's refcount, removing the mountpoint only on
+// the 1->0 transition. If RemoveAll fails, the refcount is rolled back
+// to its pre-call value so a later retry can try again.
+func (s *Store) Unmount(logger lager.Logger, name string) error {
+	logger = logger.Session("unmount", lager.Data{"volume": name})
+
+	v, found := s.volumes.Get(name)
+	if !found {
+		return fmt.Errorf("Volume '%s' not found", name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.refCount == 0 {
+		return fmt.Errorf("Volume '%s' not previously mounted", name)
+	}
+
+	mountPath := s.mountPath(logger, v.VolumeID)
+
+	if _, err := s.fileSystem.Stat(mountPath); err != nil {
+		return err
+	}
+
+	v.refCount--
+	if v.refCount == 0 {
+		if err := s.fileSystem.RemoveAll(mountPath); err != nil {
+			logger.Error("remove-mountpath-failed", err)
+			v.refCount++
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns the volume registered under name, if any.
+func (s *Store) Get(name string) (*Volume, bool) {
+	return s.volumes.Get(name)
+}
+
+// MountCount reports v's current mount refcount.
+func (v *Volume) MountCount() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.refCount
+}
+
+// List returns every volume for which filter reports true. A nil filter
+// matches everything.
+func (s *Store) List(filter Filter) []*Volume {
+	volumes := []*Volume{}
+	for _, name := range s.volumes.Keys() {
+		v, found := s.volumes.Get(name)
+		if !found {
+			continue
+		}
+		if filter == nil || filter(v) {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes
+}
+
+// Warnings reports discrepancies between the store's in-memory volumes
+// and the volume directories actually present on disk under
+// mountDir/_volumes - one warning per volume directory with no backing
+// registration and one per registered volume missing its directory.
+// These are surfaced to callers of List rather than failing it outright,
+// mirroring moby's Backend.List warnings.
+func (s *Store) Warnings(logger lager.Logger) []string {
+	logger = logger.Session("warnings")
+
+	if snapshot, err := s.volumes.MarshalJSON(); err == nil {
+		logger.Debug("known-volumes", lager.Data{"volumes": json.RawMessage(snapshot)})
+	}
+
+	warnings := []string{}
+
+	dir, err := s.fileSystem.Abs(s.mountDir)
+	if err != nil {
+		logger.Error("abs-failed", err)
+		dir = s.mountDir
+	}
+
+	entries, err := s.fileSystem.ReadDir(path.Join(dir, VolumesRootDir))
+	if err != nil {
+		return warnings
+	}
+
+	onDisk := map[string]bool{}
+	for _, entry := range entries {
+		onDisk[entry.Name()] = true
+	}
+
+	known := map[string]bool{}
+	for _, name := range s.volumes.Keys() {
+		v, found := s.volumes.Get(name)
+		if !found {
+			continue
+		}
+		known[v.VolumeID] = true
+		if !onDisk[v.VolumeID] {
+			warnings = append(warnings, fmt.Sprintf("volume %s has no backing directory on disk", name))
+		}
+	}
+
+	for volumeID := range onDisk {
+		if !known[volumeID] {
+			warnings = append(warnings, fmt.Sprintf("volume directory %s has no registered volume", volumeID))
+		}
+	}
+
+	return warnings
+}
+
+// Prune removes every volume matching filter with a current mount count
+// of zero, returning the names deleted and the total size of their
+// backing directories (best-effort; a directory whose size can't be
+// determined is reported as zero). Mounted volumes are left untouched
+// regardless of whether they match filter.
+func (s *Store) Prune(logger lager.Logger, filter Filter) ([]string, int64, error) {
+	logger = logger.Session("prune")
+
+	deleted := []string{}
+	var spaceReclaimed int64
+
+	for _, v := range s.List(filter) {
+		if v.MountCount() > 0 {
+			continue
+		}
+
+		spaceReclaimed += s.diskUsage(logger, v.VolumeID)
+
+		if err := s.Remove(logger, v.Name, false); err != nil {
+			logger.Error("remove-failed", err, lager.Data{"volume": v.Name})
+			return deleted, spaceReclaimed, err
+		}
+		deleted = append(deleted, v.Name)
+	}
+
+	return deleted, spaceReclaimed, nil
+}
+
+// diskUsage best-effort sums the size of volumeID's backing directory,
+// descending into nested directories. Errors are swallowed and reported
+// as zero usage, since Prune's job is to free space, not to account for
+// it precisely.
+func (s *Store) diskUsage(logger lager.Logger, volumeID string) int64 {
+	var total int64
+	dirs := []string{s.volumePath(logger, volumeID)}
+	for len(dirs) > 0 {
+		dir := dirs[len(dirs)-1]
+		dirs = dirs[:len(dirs)-1]
+
+		entries, err := s.fileSystem.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, path.Join(dir, entry.Name()))
+				continue
+			}
+			total += entry.Size()
+		}
+	}
+	return total
+}
+
+// Remove unmounts (if mounted) and deletes the volume registered under
+// name, removing its backing directory from disk. If force is false, a
+// currently mounted volume is left untouched and an error is returned;
+// if force is true, it is unmounted down to zero before being removed.
+func (s *Store) Remove(logger lager.Logger, name string, force bool) error {
+	logger = logger.Session("remove", lager.Data{"volume": name})
+
+	v, found := s.volumes.Get(name)
+	if !found {
+		return fmt.Errorf("Volume '%s' not found", name)
+	}
+
+	if v.MountCount() > 0 {
+		if !force {
+			return fmt.Errorf("Volume '%s' is still mounted", name)
+		}
+		for v.MountCount() > 0 {
+			if err := s.Unmount(logger, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	volumePath := s.volumePath(logger, v.VolumeID)
+	if err := s.fileSystem.RemoveAll(volumePath); err != nil {
+		logger.Error("remove-volume-dir-failed", err)
+		return err
+	}
+
+	s.volumes.Delete(name)
+	return nil
+}
+
+// FilterByDriver returns a Filter matching volumes created by the given
+// driver name, mirroring docker's volume/store helper of the same name
+// for callers that aggregate volumes across multiple drivers.
+func FilterByDriver(driverName string) Filter {
+	return func(v *Volume) bool {
+		return v.DriverName == driverName
+	}
+}
+
+func (s *Store) volumePath(logger lager.Logger, volumeID string) string {
+	dir, err := s.fileSystem.Abs(s.mountDir)
+	if err != nil {
+		logger.Error("abs-failed", err)
+		dir = s.mountDir
+	}
+	return path.Join(dir, VolumesRootDir, volumeID)
+}
+
+func (s *Store) mountPath(logger lager.Logger, volumeID string) string {
+	dir, err := s.fileSystem.Abs(s.mountDir)
+	if err != nil {
+		logger.Error("abs-failed", err)
+		dir = s.mountDir
+	}
+	return path.Join(dir, MountsRootDir, volumeID)
+}
+
+// MountPath exposes the mountpoint path computation for callers (like
+// LocalDriver) that need to report a volume's mountpoint without going
+// through Mount itself.
+func (s *Store) MountPath(logger lager.Logger, volumeID string) string {
+	return s.mountPath(logger, volumeID)
+}
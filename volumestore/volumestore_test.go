@@ -0,0 +1,243 @@
+package volumestore_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/cloudfoundry-incubator/localdriver/localdriverfakes"
+	"github.com/cloudfoundry-incubator/localdriver/volumestore"
+)
+
+func newStore(t *testing.T, fs *localdriverfakes.FakeFileSystem) *volumestore.Store {
+	t.Helper()
+	s := volumestore.NewStore(fs, "/path/to/mount")
+	if err := s.Create(lagertest.NewTestLogger("volumestore"), "test-volume", "test-volume-id", "", nil); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	return s
+}
+
+func TestConcurrentMountsThenUnmountsOnlyTouchFilesystemOnTransitions(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	store := newStore(t, fs)
+
+	const n = 25
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Mount(logger, "test-volume"); err != nil {
+				t.Errorf("mount: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := fs.SymlinkCallCount(); got != 1 {
+		t.Fatalf("expected exactly one Symlink across %d concurrent mounts, got %d", n, got)
+	}
+
+	v, _ := store.Get("test-volume")
+	if got := v.MountCount(); got != n {
+		t.Fatalf("expected refcount %d, got %d", n, got)
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Unmount(logger, "test-volume"); err != nil {
+				t.Errorf("unmount: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := fs.RemoveAllCallCount(); got != 1 {
+		t.Fatalf("expected exactly one RemoveAll across %d concurrent unmounts, got %d", n, got)
+	}
+	if got := v.MountCount(); got != 0 {
+		t.Fatalf("expected refcount 0, got %d", got)
+	}
+}
+
+func TestConcurrentCreatesOfTheSameVolumeRegisterOnlyOneVolume(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	store := volumestore.NewStore(fs, "/path/to/mount")
+
+	const n = 25
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Create(logger, "test-volume", "test-volume-id", "", nil); err != nil {
+				t.Errorf("create: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, found := store.Get("test-volume")
+	if !found {
+		t.Fatal("expected test-volume to be registered")
+	}
+
+	if _, err := store.Mount(logger, "test-volume"); err != nil {
+		t.Fatalf("mount: %s", err)
+	}
+	if got := v.MountCount(); got != 1 {
+		t.Fatalf("expected the winning *Volume to carry the mount, got refcount %d", got)
+	}
+}
+
+func TestMountRollsBackRefcountOnFailure(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	fs.SymlinkReturns(errors.New("symlink exploded"))
+	store := newStore(t, fs)
+
+	if _, err := store.Mount(logger, "test-volume"); err == nil {
+		t.Fatal("expected Mount to fail")
+	}
+
+	v, _ := store.Get("test-volume")
+	if got := v.MountCount(); got != 0 {
+		t.Fatalf("expected refcount to stay 0 after failed mount, got %d", got)
+	}
+
+	// A retry with a working filesystem should succeed cleanly - no
+	// dangling state from the failed attempt should require an Unmount
+	// first.
+	fs.SymlinkReturns(nil)
+	if _, err := store.Mount(logger, "test-volume"); err != nil {
+		t.Fatalf("expected retry to succeed, got %s", err)
+	}
+	if got := v.MountCount(); got != 1 {
+		t.Fatalf("expected refcount 1 after successful retry, got %d", got)
+	}
+}
+
+func TestUnmountRollsBackRefcountOnFailure(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	store := newStore(t, fs)
+
+	if _, err := store.Mount(logger, "test-volume"); err != nil {
+		t.Fatalf("mount: %s", err)
+	}
+
+	fs.RemoveAllReturns(errors.New("remove exploded"))
+	if err := store.Unmount(logger, "test-volume"); err == nil {
+		t.Fatal("expected Unmount to fail")
+	}
+
+	v, _ := store.Get("test-volume")
+	if got := v.MountCount(); got != 1 {
+		t.Fatalf("expected refcount to stay 1 after failed unmount, got %d", got)
+	}
+
+	fs.RemoveAllReturns(nil)
+	if err := store.Unmount(logger, "test-volume"); err != nil {
+		t.Fatalf("expected retry to succeed, got %s", err)
+	}
+	if got := v.MountCount(); got != 0 {
+		t.Fatalf("expected refcount 0 after successful retry, got %d", got)
+	}
+}
+
+func TestPruneOnlyRemovesUnmountedVolumes(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	store := newStore(t, fs)
+	if err := store.Create(logger, "other-volume", "other-volume-id", "", nil); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	if _, err := store.Mount(logger, "test-volume"); err != nil {
+		t.Fatalf("mount: %s", err)
+	}
+
+	deleted, _, err := store.Prune(logger, nil)
+	if err != nil {
+		t.Fatalf("prune: %s", err)
+	}
+
+	if got := []string{"other-volume"}; !equalStrings(deleted, got) {
+		t.Fatalf("expected %v deleted, got %v", got, deleted)
+	}
+	if _, found := store.Get("test-volume"); !found {
+		t.Fatal("expected mounted volume to survive Prune")
+	}
+	if _, found := store.Get("other-volume"); found {
+		t.Fatal("expected unmounted volume to be removed by Prune")
+	}
+}
+
+func TestWarningsReportsOrphanedOnDiskDirectory(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	fs.AbsReturns("/path/to/mount", nil)
+	fs.ReadDirReturns([]os.FileInfo{fakeFileInfo{name: "orphaned-id"}}, nil)
+	store := newStore(t, fs)
+
+	warnings := store.Warnings(logger)
+	found := false
+	for _, w := range warnings {
+		if strings.Contains(w, "orphaned-id") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a warning about orphaned-id, got %v", warnings)
+	}
+}
+
+type fakeFileInfo struct{ name string }
+
+func (f fakeFileInfo) Name() string       { return f.name }
+func (f fakeFileInfo) Size() int64        { return 0 }
+func (f fakeFileInfo) Mode() os.FileMode  { return 0 }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUnmountOfNeverMountedVolumeDoesNotTouchFilesystem(t *testing.T) {
+	logger := lagertest.NewTestLogger("volumestore")
+	fs := &localdriverfakes.FakeFileSystem{}
+	store := newStore(t, fs)
+
+	if err := store.Unmount(logger, "test-volume"); err == nil {
+		t.Fatal("expected Unmount of a never-mounted volume to fail")
+	}
+	if got := fs.StatCallCount(); got != 0 {
+		t.Fatalf("expected Unmount to short-circuit before Stat, got %d calls", got)
+	}
+}